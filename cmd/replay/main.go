@@ -0,0 +1,75 @@
+// Command replay re-attempts the write for every observation quarantined in
+// the dead-letter topic, for use after fixing whatever caused the
+// BigQuery/Firestore/Postgres writes to fail. It doesn't rerun detection:
+// the dead-lettered payload already carries the fully analyzed row.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/line_analyzer/functions"
+	"github.com/pkg/errors"
+)
+
+// dlqMessage mirrors just enough of the wire format functions.dlqMessage
+// publishes to log which object was replayed; it's redeclared here since
+// that type is unexported. The full payload is passed through to
+// functions.ReplayRow unparsed.
+type dlqMessage struct {
+	ObjectPath string `json:"object_path"`
+}
+
+func main() {
+	ctx := context.Background()
+	if err := run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context) error {
+	projectID := mustEnv("GCP_PROJECT_ID")
+	subscriptionID := mustEnv("REPLAY_SUBSCRIPTION")
+
+	client, err := pubsub.NewClient(ctx, projectID)
+	if err != nil {
+		return errors.Wrap(err, "init pubsub client failed")
+	}
+	sub := client.Subscription(subscriptionID)
+
+	// Pull messages for up to replayDuration, replaying each before acking
+	// it so a crash mid-run just redelivers the unacked messages.
+	cctx, cancel := context.WithTimeout(ctx, replayDuration)
+	defer cancel()
+
+	return sub.Receive(cctx, func(ctx context.Context, m *pubsub.Message) {
+		var msg dlqMessage
+		if err := json.Unmarshal(m.Data, &msg); err != nil {
+			log.Printf("unmarshal dead letter message failed: %v", err)
+			m.Nack()
+			return
+		}
+
+		if err := functions.ReplayRow(ctx, m.Data); err != nil {
+			log.Printf("replay %s failed: %v", msg.ObjectPath, err)
+			m.Nack()
+			return
+		}
+		log.Printf("replayed %s", msg.ObjectPath)
+		m.Ack()
+	})
+}
+
+const replayDuration = 5 * time.Minute
+
+func mustEnv(key string) string {
+	env := os.Getenv(key)
+	if env == "" {
+		log.Fatal(errors.Errorf("env variable with key=%s is not found", key))
+	}
+	return env
+}
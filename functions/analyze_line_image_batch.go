@@ -0,0 +1,147 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// batchConcurrencyEnvKey bounds how many images in a single batch are
+// detected concurrently. Rekognition/Vision clients tolerate concurrent
+// calls fine, but an unbounded fan-out would blow through API rate limits.
+const batchConcurrencyEnvKey = "BATCH_CONCURRENCY"
+
+const defaultBatchConcurrency = 8
+
+// pubSubMessage is the push payload GCF hands to a Pub/Sub-triggered
+// function.
+type pubSubMessage struct {
+	Data []byte `json:"data"`
+}
+
+// batchRequest is the JSON body carried in pubSubMessage.Data: a set of GCS
+// objects in the same bucket to analyze together.
+type batchRequest struct {
+	Bucket  string   `json:"bucket"`
+	Objects []string `json:"objects"`
+}
+
+// AnalyzeLineImageBatch analyzes a batch of lineObservation images referenced
+// by a Pub/Sub push, processing them concurrently through a bounded worker
+// pool and flushing all resulting rows with one BigQuery insert per table
+// instead of one per image. This amortizes per-invocation detector/client
+// setup across the whole batch, which matters once shops upload frames every
+// few seconds.
+func AnalyzeLineImageBatch(ctx context.Context, m pubSubMessage) error {
+	var req batchRequest
+	if err := json.Unmarshal(m.Data, &req); err != nil {
+		return errors.Wrap(err, "unmarshal batch request")
+	}
+
+	imageStore, err := newImageStore(ctx, req.Bucket)
+	if err != nil {
+		return errors.Wrap(err, "init image store")
+	}
+	writer, err := sharedBufferedObservationWriter(ctx)
+	if err != nil {
+		return errors.Wrap(err, "init buffered observation writer")
+	}
+
+	concurrency := defaultBatchConcurrency
+	if v := os.Getenv(batchConcurrencyEnvKey); v != "" {
+		concurrency, err = strconv.Atoi(v)
+		if err != nil {
+			return errors.Wrapf(err, "parse %s=%s", batchConcurrencyEnvKey, v)
+		}
+	}
+
+	now := time.Now()
+	type result struct {
+		lo             *lineObservation
+		customers      []*waitingCustomerMeta
+		filteredPeople []*personDetection
+		err            error
+	}
+	results := make([]result, len(req.Objects))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, objectName := range req.Objects {
+		i, objectName := i, objectName
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i].lo, results[i].customers, results[i].filteredPeople, results[i].err = analyzeBatchObject(ctx, imageStore, objectName, now)
+		}()
+	}
+	wg.Wait()
+
+	var rows []*bufferedRow
+	var ordered []result
+	var errs []error
+	for i, r := range results {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		rows = append(rows, &bufferedRow{
+			ObjectPath:  req.Bucket + "/" + req.Objects[i],
+			Observation: r.lo,
+			Customers:   r.customers,
+		})
+		ordered = append(ordered, r)
+	}
+
+	// Goroutines can finish detection out of frame order. updateTracks
+	// assumes each call is the next frame chronologically, so frames are
+	// fed to it sequentially here, sorted by ObservedAt, rather than in
+	// whatever order detection happened to complete.
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].lo.ObservedAt.Before(ordered[j].lo.ObservedAt) })
+	var tracks []*waitingCustomerTrack
+	for _, r := range ordered {
+		trks, err := updateTracks(ctx, r.lo.ShopID, r.lo.ObservedAt, r.filteredPeople)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		tracks = append(tracks, trks...)
+	}
+
+	if err := writer.PutWaitingCustomerTracks(ctx, tracks); err != nil {
+		errs = append(errs, err)
+	}
+	if err := writer.PutRows(ctx, rows); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("analyze batch: %d of %d objects failed: %v", len(errs), len(req.Objects), errs)
+	}
+	return nil
+}
+
+// analyzeBatchObject resolves the object's pipeline and detector on its own,
+// since shops within the same batch can be configured for different
+// backends.
+func analyzeBatchObject(ctx context.Context, imageStore ImageStore, objectName string, now time.Time) (*lineObservation, []*waitingCustomerMeta, []*personDetection, error) {
+	shopID, _, err := getMetaFromObjName(objectName)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "get lineObservation meta from %s", objectName)
+	}
+	pipeline, err := resolvePipeline(shopID)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "resolve pipeline for shop %s", shopID)
+	}
+	detector, err := newDetector(ctx, pipeline.DetectorBackend)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "init detector")
+	}
+	return analyzeLineImageObject(ctx, imageStore, detector, pipeline, objectName, now)
+}
@@ -2,8 +2,6 @@ package functions
 
 import (
 	"context"
-	"io"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -11,11 +9,6 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/bigquery"
-	"cloud.google.com/go/storage"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/rekognition"
 	"github.com/pkg/errors"
 	"github.com/rs/xid"
 )
@@ -52,49 +45,90 @@ type waitingCustomerMeta struct {
 
 // AnalyzeLineImage analyzes the image of lineObservation at shop.
 func AnalyzeLineImage(ctx context.Context, e gcsEvent) error {
-	now := time.Now()
+	imageStore, err := newImageStore(ctx, e.Bucket)
+	if err != nil {
+		return errors.Wrap(err, "init image store")
+	}
+	writer, err := sharedBufferedObservationWriter(ctx)
+	if err != nil {
+		return errors.Wrap(err, "init buffered observation writer")
+	}
 
-	gcsClient, err := storage.NewClient(ctx)
+	shopID, _, err := getMetaFromObjName(e.ObjectName)
 	if err != nil {
-		return errors.Wrap(err, "init gcs client")
+		return errors.Wrapf(err, "get lineObservation meta from %s", e.ObjectName)
 	}
-	bqClient, err := bigquery.NewClient(ctx, mustEnv("GCP_PROJECT_ID"))
+	pipeline, err := resolvePipeline(shopID)
+	if err != nil {
+		return errors.Wrapf(err, "resolve pipeline for shop %s", shopID)
+	}
+	detector, err := newDetector(ctx, pipeline.DetectorBackend)
+	if err != nil {
+		return errors.Wrap(err, "init detector")
+	}
+
+	lo, customers, filteredPeople, err := analyzeLineImageObject(ctx, imageStore, detector, pipeline, e.ObjectName, time.Now())
 	if err != nil {
-		return errors.Wrap(err, "init bigquery client failed")
+		return err
 	}
 
-	obj := gcsClient.Bucket(e.Bucket).Object(e.ObjectName)
+	tracks, err := updateTracks(ctx, lo.ShopID, lo.ObservedAt, filteredPeople)
+	if err != nil {
+		return err
+	}
+	if err := writer.PutWaitingCustomerTracks(ctx, tracks); err != nil {
+		return err
+	}
+	objectPath := e.Bucket + "/" + e.ObjectName
+	return writer.PutRows(ctx, []*bufferedRow{{ObjectPath: objectPath, Observation: lo, Customers: customers}})
+}
+
+// analyzeLineImageObject runs detection and pipeline post-processing for a
+// single GCS object, without writing the result anywhere or updating the
+// shop's tracker. It's shared by AnalyzeLineImage and AnalyzeLineImageBatch
+// so the single-image and batch entrypoints can't drift in behavior.
+// Callers are responsible for calling updateTracks with the returned
+// filteredPeople themselves, since AnalyzeLineImageBatch needs to do that
+// sequentially and in observedAt order across a whole batch, not per-object
+// as each detection happens to finish.
+func analyzeLineImageObject(
+	ctx context.Context,
+	imageStore ImageStore,
+	detector Detector,
+	pipeline *pipelineConfig,
+	objectName string,
+	now time.Time,
+) (*lineObservation, []*waitingCustomerMeta, []*personDetection, error) {
 	lineObservationID := xid.New().String()
-	shopID, observedAt, err := getMetaFromObjName(obj.ObjectName())
+	shopID, observedAt, err := getMetaFromObjName(objectName)
 	if err != nil {
-		return errors.Wrapf(err, "get lineObservation meta from %s", obj.ObjectName())
+		return nil, nil, nil, errors.Wrapf(err, "get lineObservation meta from %s", objectName)
 	}
 
-	reader, err := obj.NewReader(ctx)
+	reader, err := imageStore.NewReader(ctx, objectName)
 	if err != nil {
-		return errors.Wrapf(err, "new %s reader failed", obj.ObjectName())
+		return nil, nil, nil, errors.Wrapf(err, "new %s reader failed", objectName)
 	}
-	waitingCustomerNum, customers, err := detectWaitingCustomersFromImg(ctx, lineObservationID, reader)
+	defer reader.Close()
+
+	people, customers, err := detector.DetectPeople(ctx, reader)
 	if err != nil {
-		return errors.Wrapf(err, "analyze %s image failed", obj.ObjectName())
+		return nil, nil, nil, errors.Wrapf(err, "analyze %s image failed", objectName)
+	}
+	customers = filterCustomersByConfidence(customers, pipeline.CustomerConfidenceThreshold)
+	for _, cus := range customers {
+		cus.LineObservationID = lineObservationID
 	}
 
+	filteredPeople := applyPipeline(people, pipeline)
 	lo := &lineObservation{
 		ID:               lineObservationID,
 		ShopID:           shopID,
-		WaitingPeopleNum: waitingCustomerNum,
+		WaitingPeopleNum: len(filteredPeople),
 		ObservedAt:       observedAt,
 		CreatedAt:        now,
 	}
-
-	ds := bqClient.Dataset(fastLaneDataset)
-	if err := ds.Table(lineObservationTable).Inserter().Put(ctx, []*lineObservation{lo}); err != nil {
-		return errors.Wrap(err, "put analyzed lineObservation data")
-	}
-	if err := ds.Table(waitingCustomerMetaTable).Inserter().Put(ctx, customers); err != nil {
-		return errors.Wrap(err, "load analyzed lineObservation data to bq faile")
-	}
-	return nil
+	return lo, customers, filteredPeople, nil
 }
 
 func getMetaFromObjName(objName string) (shopID string, observedAt time.Time, err error) {
@@ -108,52 +142,6 @@ func getMetaFromObjName(objName string) (shopID string, observedAt time.Time, er
 	return shopID, time.Unix(unixTime, 0).Local(), err
 }
 
-func detectWaitingCustomersFromImg(ctx context.Context, lineObservationID string, imgReader io.Reader) (waitingPeopleNum int, humans []*waitingCustomerMeta, err error) {
-	sess, err := session.NewSession()
-	if err != nil {
-		return 0, nil, errors.Wrap(err, "new aws session")
-	}
-	svc := rekognition.New(sess, aws.NewConfig().WithRegion("ap-northeast-1"))
-	bytes, err := ioutil.ReadAll(imgReader)
-	if err != nil {
-		return 0, nil, errors.Wrap(err, "read img bytes from reader")
-	}
-	detectLabelOutput, err := svc.DetectLabelsWithContext(ctx, &rekognition.DetectLabelsInput{Image: &rekognition.Image{Bytes: bytes}})
-	if err != nil {
-		return 0, nil, errors.Wrap(err, "detect labels")
-	}
-	for _, label := range detectLabelOutput.Labels {
-		if *label.Confidence < 0.5 {
-			continue
-		}
-		if *label.Name == "Person" {
-			waitingPeopleNum = len(label.Instances)
-		}
-	}
-
-	detectFacesOutput, err := svc.DetectFacesWithContext(ctx, &rekognition.DetectFacesInput{
-		Attributes: []*string{aws.String("ALL")},
-		Image:      &rekognition.Image{Bytes: bytes},
-	})
-	if err != nil {
-		return 0, nil, errors.Wrap(err, "detect faces")
-	}
-
-	var customers []*waitingCustomerMeta
-	for _, faceDetail := range detectFacesOutput.FaceDetails {
-		cus := &waitingCustomerMeta{
-			LineObservationID: lineObservationID,
-			Gender:            *faceDetail.Gender.Value,
-			GenderConfidence:  *faceDetail.Gender.Confidence,
-			LowestAge:         *faceDetail.AgeRange.Low,
-			HighestAge:        *faceDetail.AgeRange.High,
-			Confidence:        *faceDetail.Confidence,
-		}
-		customers = append(customers, cus)
-	}
-	return waitingPeopleNum, customers, nil
-}
-
 func mustEnv(key string) string {
 	env := os.Getenv(key)
 	if env == "" {
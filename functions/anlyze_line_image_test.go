@@ -0,0 +1,102 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// fakeImageStore serves image bytes from an in-memory map, so tests don't
+// need a real GCS bucket.
+type fakeImageStore struct {
+	objects map[string][]byte
+}
+
+func (s *fakeImageStore) NewReader(_ context.Context, objectName string) (io.ReadCloser, error) {
+	data, ok := s.objects[objectName]
+	if !ok {
+		return nil, errors.Errorf("object %s not found", objectName)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// fakeDetector returns a canned detection result regardless of the image
+// bytes it's given, so tests can exercise analyzeLineImageObject without a
+// real Rekognition/Vision/local detector.
+type fakeDetector struct {
+	people  []*personDetection
+	faces   []*waitingCustomerMeta
+	gotRead []byte
+}
+
+func (d *fakeDetector) DetectPeople(_ context.Context, imgReader io.Reader) ([]*personDetection, []*waitingCustomerMeta, error) {
+	data, err := ioutil.ReadAll(imgReader)
+	if err != nil {
+		return nil, nil, err
+	}
+	d.gotRead = data
+	return d.people, d.faces, nil
+}
+
+func TestAnalyzeLineImageObject(t *testing.T) {
+	imageStore := &fakeImageStore{objects: map[string][]byte{
+		"shop1_1600000000.jpg": []byte("image-bytes"),
+	}}
+	detector := &fakeDetector{
+		people: []*personDetection{
+			{Label: "Person", Confidence: 0.9, BoundingBox: boundingBox{Left: 0.1, Top: 0.1, Width: 0.2, Height: 0.2}},
+			{Label: "Person", Confidence: 0.2, BoundingBox: boundingBox{Left: 0.5, Top: 0.5, Width: 0.2, Height: 0.2}},
+		},
+		faces: []*waitingCustomerMeta{
+			{Gender: "Male", Confidence: 0.8},
+			{Gender: "Female", Confidence: 0.3},
+		},
+	}
+	pipeline := &pipelineConfig{
+		ConfidenceThreshold:         0.5,
+		CountLabel:                  "Person",
+		CustomerConfidenceThreshold: 0.5,
+	}
+
+	lo, customers, filteredPeople, err := analyzeLineImageObject(context.Background(), imageStore, detector, pipeline, "shop1_1600000000.jpg", time.Now())
+	if err != nil {
+		t.Fatalf("analyzeLineImageObject: %v", err)
+	}
+
+	if lo.ShopID != "shop1" {
+		t.Errorf("ShopID = %q, want %q", lo.ShopID, "shop1")
+	}
+	if !lo.ObservedAt.Equal(time.Unix(1600000000, 0).Local()) {
+		t.Errorf("ObservedAt = %v, want %v", lo.ObservedAt, time.Unix(1600000000, 0).Local())
+	}
+	if len(filteredPeople) != 1 {
+		t.Fatalf("filteredPeople = %d, want 1 (low-confidence detection should be filtered)", len(filteredPeople))
+	}
+	if lo.WaitingPeopleNum != 1 {
+		t.Errorf("WaitingPeopleNum = %d, want 1", lo.WaitingPeopleNum)
+	}
+	if len(customers) != 1 || customers[0].Gender != "Male" {
+		t.Fatalf("customers = %+v, want only the high-confidence Male face", customers)
+	}
+	if customers[0].LineObservationID != lo.ID {
+		t.Errorf("customers[0].LineObservationID = %q, want %q", customers[0].LineObservationID, lo.ID)
+	}
+	if string(detector.gotRead) != "image-bytes" {
+		t.Errorf("detector read %q, want %q", detector.gotRead, "image-bytes")
+	}
+}
+
+func TestAnalyzeLineImageObject_ImageStoreError(t *testing.T) {
+	imageStore := &fakeImageStore{objects: map[string][]byte{}}
+	detector := &fakeDetector{}
+	pipeline := defaultPipelineConfig
+
+	if _, _, _, err := analyzeLineImageObject(context.Background(), imageStore, detector, pipeline, "shop1_1600000000.jpg", time.Now()); err == nil {
+		t.Fatal("expected an error when the image store has no such object")
+	}
+}
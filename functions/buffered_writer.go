@@ -0,0 +1,171 @@
+package functions
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const maxFlushRetries = 3
+
+// bufferedRow is one image's analysis result, ready to be written to the
+// ObservationRepository.
+type bufferedRow struct {
+	ObjectPath  string
+	Observation *lineObservation
+	Customers   []*waitingCustomerMeta
+}
+
+// BufferedObservationWriter writes rows to an ObservationRepository,
+// retrying transient failures with exponential backoff. Rows that still
+// fail after retrying are routed to a DeadLetterSink along with the GCS
+// object path they came from, so a single transient write failure no longer
+// loses the observation. It intentionally doesn't buffer rows across
+// invocations: Cloud Functions instances can be recycled at any time
+// between requests, with no background work running in between, so any row
+// held only in memory past the end of one invocation could be lost with no
+// error and no dead-letter entry.
+type BufferedObservationWriter struct {
+	repo ObservationRepository
+	dlq  DeadLetterSink
+}
+
+// NewBufferedObservationWriter builds a writer that writes to repo and
+// sends permanently-failed rows to dlq.
+func NewBufferedObservationWriter(repo ObservationRepository, dlq DeadLetterSink) (*BufferedObservationWriter, error) {
+	return &BufferedObservationWriter{repo: repo, dlq: dlq}, nil
+}
+
+var (
+	bufferedWriterOnce sync.Once
+	bufferedWriter     *BufferedObservationWriter
+	bufferedWriterErr  error
+)
+
+// sharedBufferedObservationWriter lazily builds the single
+// BufferedObservationWriter used by every AnalyzeLineImage/
+// AnalyzeLineImageBatch invocation in a warm container, so its repository
+// and DLQ clients are reused instead of reconnecting every time.
+func sharedBufferedObservationWriter(ctx context.Context) (*BufferedObservationWriter, error) {
+	bufferedWriterOnce.Do(func() {
+		repo, err := newObservationRepository(ctx)
+		if err != nil {
+			bufferedWriterErr = errors.Wrap(err, "init observation repository")
+			return
+		}
+		dlq, err := newPubSubDeadLetterSink(ctx)
+		if err != nil {
+			bufferedWriterErr = errors.Wrap(err, "init dead letter sink")
+			return
+		}
+		bufferedWriter, bufferedWriterErr = NewBufferedObservationWriter(repo, dlq)
+	})
+	return bufferedWriter, bufferedWriterErr
+}
+
+// PutWaitingCustomerTracks writes retired track rows, retrying transient
+// failures with exponential backoff and routing them to the DeadLetterSink
+// if they still fail. Without this, a single transient BigQuery error would
+// permanently lose a track's dwell-time row: by the time it's retired, the
+// tracker state update that removed it from the active set is already
+// durably committed in Firestore, so there's no other record of it.
+func (w *BufferedObservationWriter) PutWaitingCustomerTracks(ctx context.Context, tracks []*waitingCustomerTrack) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	err := w.putTracksWithRetry(ctx, tracks)
+	if err == nil {
+		return nil
+	}
+
+	if dlqErr := w.dlq.SendTracks(ctx, tracks); dlqErr != nil {
+		return errors.Wrapf(dlqErr, "send %d tracks to dead letter queue after put failed: %s", len(tracks), err)
+	}
+	return nil
+}
+
+func (w *BufferedObservationWriter) putTracksWithRetry(ctx context.Context, tracks []*waitingCustomerTrack) error {
+	var lastErr error
+	for attempt := 0; attempt < maxFlushRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(math.Pow(2, float64(attempt))) * time.Second
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err := w.repo.PutWaitingCustomerTracks(ctx, tracks); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return errors.Wrapf(lastErr, "put %d tracks failed after %d attempts", len(tracks), maxFlushRetries)
+}
+
+// PutRows writes rows to the repository, retrying transient failures with
+// exponential backoff and quarantining the whole set via the DeadLetterSink
+// if it still fails. Used directly by both AnalyzeLineImage (a batch of
+// one row) and AnalyzeLineImageBatch (a full Pub/Sub batch), since neither
+// entrypoint can assume anything handed here will still be around by a
+// later call.
+func (w *BufferedObservationWriter) PutRows(ctx context.Context, rows []*bufferedRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	err := w.putWithRetry(ctx, rows)
+	if err == nil {
+		return nil
+	}
+
+	for _, row := range rows {
+		if dlqErr := w.dlq.Send(ctx, row.ObjectPath, row.Observation, row.Customers); dlqErr != nil {
+			return errors.Wrapf(dlqErr, "send %s to dead letter queue after put failed: %s", row.ObjectPath, err)
+		}
+	}
+	return nil
+}
+
+func (w *BufferedObservationWriter) putWithRetry(ctx context.Context, rows []*bufferedRow) error {
+	observations := make([]*lineObservation, len(rows))
+	var customers []*waitingCustomerMeta
+	for i, row := range rows {
+		observations[i] = row.Observation
+		customers = append(customers, row.Customers...)
+	}
+
+	var lastErr error
+	observationsPut := false
+	for attempt := 0; attempt < maxFlushRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(math.Pow(2, float64(attempt))) * time.Second
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		// Only re-issue PutLineObservations if it hasn't already succeeded:
+		// otherwise a retry triggered by a PutWaitingCustomerMetas failure
+		// would insert the same observations into BigQuery a second time.
+		if !observationsPut {
+			if err := w.repo.PutLineObservations(ctx, observations); err != nil {
+				lastErr = err
+				continue
+			}
+			observationsPut = true
+		}
+		if err := w.repo.PutWaitingCustomerMetas(ctx, customers); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return errors.Wrapf(lastErr, "put batch of %d observations failed after %d attempts", len(observations), maxFlushRetries)
+}
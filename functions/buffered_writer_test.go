@@ -0,0 +1,122 @@
+package functions
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeObservationRepository counts every Put call and fails the first
+// failMetasUntilCall calls to PutWaitingCustomerMetas and the first
+// failTracksUntilCall calls to PutWaitingCustomerTracks.
+type fakeObservationRepository struct {
+	observationsPutCalls int
+	metasPutCalls        int
+	tracksPutCalls       int
+	failMetasUntilCall   int
+	failTracksUntilCall  int
+}
+
+func (r *fakeObservationRepository) PutLineObservations(_ context.Context, _ []*lineObservation) error {
+	r.observationsPutCalls++
+	return nil
+}
+
+func (r *fakeObservationRepository) PutWaitingCustomerMetas(_ context.Context, _ []*waitingCustomerMeta) error {
+	r.metasPutCalls++
+	if r.metasPutCalls <= r.failMetasUntilCall {
+		return errTransient
+	}
+	return nil
+}
+
+func (r *fakeObservationRepository) PutWaitingCustomerTracks(_ context.Context, _ []*waitingCustomerTrack) error {
+	r.tracksPutCalls++
+	if r.tracksPutCalls <= r.failTracksUntilCall {
+		return errTransient
+	}
+	return nil
+}
+
+type fakeDeadLetterSink struct {
+	sent       []string
+	sentTracks [][]*waitingCustomerTrack
+}
+
+func (s *fakeDeadLetterSink) Send(_ context.Context, objectPath string, _ *lineObservation, _ []*waitingCustomerMeta) error {
+	s.sent = append(s.sent, objectPath)
+	return nil
+}
+
+func (s *fakeDeadLetterSink) SendTracks(_ context.Context, tracks []*waitingCustomerTrack) error {
+	s.sentTracks = append(s.sentTracks, tracks)
+	return nil
+}
+
+var errTransient = transientError("transient failure")
+
+type transientError string
+
+func (e transientError) Error() string { return string(e) }
+
+func TestBufferedObservationWriter_PutRows_RetriesWithoutDuplicatingObservations(t *testing.T) {
+	repo := &fakeObservationRepository{failMetasUntilCall: 1}
+	dlq := &fakeDeadLetterSink{}
+	w, err := NewBufferedObservationWriter(repo, dlq)
+	if err != nil {
+		t.Fatalf("NewBufferedObservationWriter: %v", err)
+	}
+
+	rows := []*bufferedRow{{ObjectPath: "bucket/obj.jpg", Observation: &lineObservation{ID: "obs1"}}}
+	if err := w.PutRows(context.Background(), rows); err != nil {
+		t.Fatalf("PutRows: %v", err)
+	}
+
+	if repo.observationsPutCalls != 1 {
+		t.Errorf("PutLineObservations called %d times, want 1 (should not be re-issued once it succeeds)", repo.observationsPutCalls)
+	}
+	if repo.metasPutCalls != 2 {
+		t.Errorf("PutWaitingCustomerMetas called %d times, want 2 (one failure, one retry)", repo.metasPutCalls)
+	}
+	if len(dlq.sent) != 0 {
+		t.Errorf("sent to dead letter sink %v, want none since the retry succeeded", dlq.sent)
+	}
+}
+
+func TestBufferedObservationWriter_PutRows_DeadLettersAfterExhaustingRetries(t *testing.T) {
+	repo := &fakeObservationRepository{failMetasUntilCall: maxFlushRetries}
+	dlq := &fakeDeadLetterSink{}
+	w, err := NewBufferedObservationWriter(repo, dlq)
+	if err != nil {
+		t.Fatalf("NewBufferedObservationWriter: %v", err)
+	}
+
+	rows := []*bufferedRow{{ObjectPath: "bucket/obj.jpg", Observation: &lineObservation{ID: "obs1"}}}
+	if err := w.PutRows(context.Background(), rows); err != nil {
+		t.Fatalf("PutRows: %v", err)
+	}
+
+	if len(dlq.sent) != 1 || dlq.sent[0] != "bucket/obj.jpg" {
+		t.Errorf("sent to dead letter sink %v, want [bucket/obj.jpg]", dlq.sent)
+	}
+}
+
+func TestBufferedObservationWriter_PutWaitingCustomerTracks_RetriesThenDeadLetters(t *testing.T) {
+	repo := &fakeObservationRepository{failTracksUntilCall: maxFlushRetries}
+	dlq := &fakeDeadLetterSink{}
+	w, err := NewBufferedObservationWriter(repo, dlq)
+	if err != nil {
+		t.Fatalf("NewBufferedObservationWriter: %v", err)
+	}
+
+	tracks := []*waitingCustomerTrack{{TrackID: "track1"}}
+	if err := w.PutWaitingCustomerTracks(context.Background(), tracks); err != nil {
+		t.Fatalf("PutWaitingCustomerTracks: %v", err)
+	}
+
+	if repo.tracksPutCalls != maxFlushRetries {
+		t.Errorf("PutWaitingCustomerTracks called %d times, want %d", repo.tracksPutCalls, maxFlushRetries)
+	}
+	if len(dlq.sentTracks) != 1 || len(dlq.sentTracks[0]) != 1 || dlq.sentTracks[0][0].TrackID != "track1" {
+		t.Errorf("sent to dead letter sink %+v, want the one track that never wrote", dlq.sentTracks)
+	}
+}
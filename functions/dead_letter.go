@@ -0,0 +1,95 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/pkg/errors"
+)
+
+const deadLetterTopicEnvKey = "DEAD_LETTER_TOPIC"
+
+// dlqMessage is the payload published to the dead-letter topic: enough to
+// both inspect what failed to write and, via cmd/replay, re-attempt that
+// write. Replay writes the stored rows as they are; it does not re-run the
+// analyzer against the original object. Exactly one of (Observation, Tracks)
+// is set, depending on whether Send or SendTracks produced it.
+type dlqMessage struct {
+	ObjectPath  string                  `json:"object_path"`
+	Observation *lineObservation        `json:"observation"`
+	Customers   []*waitingCustomerMeta  `json:"customers"`
+	Tracks      []*waitingCustomerTrack `json:"tracks"`
+}
+
+// DeadLetterSink quarantines rows that permanently failed to write: either
+// an observation (keyed by the GCS object path it was derived from) or a
+// batch of retired tracks.
+type DeadLetterSink interface {
+	Send(ctx context.Context, objectPath string, lo *lineObservation, customers []*waitingCustomerMeta) error
+	SendTracks(ctx context.Context, tracks []*waitingCustomerTrack) error
+}
+
+// pubsubDeadLetterSink publishes failed rows to a Pub/Sub topic, where
+// cmd/replay can pick them back up.
+type pubsubDeadLetterSink struct {
+	topic *pubsub.Topic
+}
+
+func newPubSubDeadLetterSink(ctx context.Context) (*pubsubDeadLetterSink, error) {
+	client, err := pubsub.NewClient(ctx, mustEnv("GCP_PROJECT_ID"))
+	if err != nil {
+		return nil, errors.Wrap(err, "init pubsub client failed")
+	}
+	return &pubsubDeadLetterSink{topic: client.Topic(mustEnv(deadLetterTopicEnvKey))}, nil
+}
+
+func (s *pubsubDeadLetterSink) Send(ctx context.Context, objectPath string, lo *lineObservation, customers []*waitingCustomerMeta) error {
+	data, err := json.Marshal(dlqMessage{ObjectPath: objectPath, Observation: lo, Customers: customers})
+	if err != nil {
+		return errors.Wrap(err, "marshal dead letter message")
+	}
+	result := s.topic.Publish(ctx, &pubsub.Message{Data: data})
+	if _, err := result.Get(ctx); err != nil {
+		return errors.Wrapf(err, "publish dead letter message for %s", objectPath)
+	}
+	return nil
+}
+
+// SendTracks quarantines a batch of retired tracks whose waiting_customer_track
+// write permanently failed. Unlike Send, there's no GCS object to key this
+// on: by the time a track retires, the frame(s) that produced it may already
+// be long written or dead-lettered on their own.
+func (s *pubsubDeadLetterSink) SendTracks(ctx context.Context, tracks []*waitingCustomerTrack) error {
+	data, err := json.Marshal(dlqMessage{Tracks: tracks})
+	if err != nil {
+		return errors.Wrap(err, "marshal dead letter message")
+	}
+	result := s.topic.Publish(ctx, &pubsub.Message{Data: data})
+	if _, err := result.Get(ctx); err != nil {
+		return errors.Wrapf(err, "publish dead letter message for %d tracks", len(tracks))
+	}
+	return nil
+}
+
+// ReplayRow re-attempts writing a single dead-lettered row or track batch,
+// given the raw dlqMessage payload as published to the dead-letter topic,
+// for use by cmd/replay. It only re-attempts the write: detection and the
+// shop's tracker already ran successfully once, before the write failed,
+// and re-running them here would detect and track the same frame a second
+// time.
+func ReplayRow(ctx context.Context, payload []byte) error {
+	var msg dlqMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return errors.Wrap(err, "unmarshal dead letter message")
+	}
+	writer, err := sharedBufferedObservationWriter(ctx)
+	if err != nil {
+		return errors.Wrap(err, "init buffered observation writer")
+	}
+	if msg.Tracks != nil {
+		return writer.PutWaitingCustomerTracks(ctx, msg.Tracks)
+	}
+	row := &bufferedRow{ObjectPath: msg.ObjectPath, Observation: msg.Observation, Customers: msg.Customers}
+	return writer.PutRows(ctx, []*bufferedRow{row})
+}
@@ -0,0 +1,67 @@
+package functions
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// detectorBackendEnvKey selects which Detector implementation is used by
+// AnalyzeLineImage. Valid values are "rekognition" (default), "gcp_vision"
+// and "local". This lets a deployment avoid AWS entirely or A/B test
+// detectors without a code change.
+const detectorBackendEnvKey = "DETECTOR_BACKEND"
+
+const (
+	detectorBackendRekognition = "rekognition"
+	detectorBackendGCPVision   = "gcp_vision"
+	detectorBackendLocal       = "local"
+)
+
+// boundingBox is a detection's location within the image, expressed as
+// fractions of the image width/height (matching Rekognition's convention).
+type boundingBox struct {
+	Left   float64
+	Top    float64
+	Width  float64
+	Height float64
+}
+
+// personDetection is a single labeled detection in the image, e.g. a
+// "Person" or "Face" instance, before any pipeline post-processing (see
+// pipeline.go) is applied.
+type personDetection struct {
+	Label       string
+	Confidence  float64
+	BoundingBox boundingBox
+}
+
+// Detector detects waiting customers in an image and reports both the raw
+// labeled detections (for pipeline post-processing) and per-face metadata
+// used to populate waitingCustomerMeta rows. Confidence filtering, ROI
+// restriction and dedup are applied by the caller via the resolved
+// pipelineConfig, not by the Detector itself.
+type Detector interface {
+	DetectPeople(ctx context.Context, imgReader io.Reader) (people []*personDetection, faces []*waitingCustomerMeta, err error)
+}
+
+// newDetector builds the Detector for backend, falling back to the
+// DETECTOR_BACKEND env var when backend is empty so a shop's pipeline entry
+// can override the deployment-wide default.
+func newDetector(ctx context.Context, backend string) (Detector, error) {
+	if backend == "" {
+		backend = os.Getenv(detectorBackendEnvKey)
+	}
+	switch backend {
+	case "", detectorBackendRekognition:
+		return newRekognitionDetector()
+	case detectorBackendGCPVision:
+		return newGCPVisionDetector(ctx)
+	case detectorBackendLocal:
+		return newLocalDetector()
+	default:
+		return nil, errors.Errorf("unknown %s=%s", detectorBackendEnvKey, backend)
+	}
+}
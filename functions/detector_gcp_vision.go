@@ -0,0 +1,106 @@
+package functions
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	vision "cloud.google.com/go/vision/apiv1"
+	"github.com/pkg/errors"
+	visionpb "google.golang.org/genproto/googleapis/cloud/vision/v1"
+)
+
+// gcpVisionDetector detects people and faces using Google Cloud Vision,
+// useful for deployments that want to stay single-cloud on GCP.
+type gcpVisionDetector struct {
+	client *vision.ImageAnnotatorClient
+}
+
+var (
+	visionClientOnce sync.Once
+	visionClient     *vision.ImageAnnotatorClient
+	visionClientErr  error
+)
+
+// sharedVisionClient lazily creates a single Vision client shared by every
+// gcpVisionDetector, since vision.NewImageAnnotatorClient was previously
+// (and wastefully) called once per image, including inside
+// AnalyzeLineImageBatch's concurrent worker pool.
+func sharedVisionClient(ctx context.Context) (*vision.ImageAnnotatorClient, error) {
+	visionClientOnce.Do(func() {
+		visionClient, visionClientErr = vision.NewImageAnnotatorClient(ctx)
+	})
+	return visionClient, visionClientErr
+}
+
+func newGCPVisionDetector(ctx context.Context) (*gcpVisionDetector, error) {
+	client, err := sharedVisionClient(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "new vision client")
+	}
+	return &gcpVisionDetector{client: client}, nil
+}
+
+func (d *gcpVisionDetector) DetectPeople(ctx context.Context, imgReader io.Reader) ([]*personDetection, []*waitingCustomerMeta, error) {
+	bytes, err := ioutil.ReadAll(imgReader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read img bytes from reader")
+	}
+	img := &visionpb.Image{Content: bytes}
+
+	objects, err := d.client.LocalizeObjects(ctx, img, nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "localize objects")
+	}
+	var people []*personDetection
+	for _, obj := range objects {
+		people = append(people, &personDetection{
+			Label:       obj.Name,
+			Confidence:  float64(obj.Score),
+			BoundingBox: normalizedVerticesToBoundingBox(obj.BoundingPoly.GetNormalizedVertices()),
+		})
+	}
+
+	faceAnnotations, err := d.client.DetectFaces(ctx, img, nil, 100)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "detect faces")
+	}
+	var customers []*waitingCustomerMeta
+	for _, face := range faceAnnotations {
+		// Cloud Vision doesn't return gender/age, unlike Rekognition, so those
+		// fields are left at their zero values for this backend.
+		customers = append(customers, &waitingCustomerMeta{
+			Confidence: float64(face.DetectionConfidence),
+		})
+	}
+	return people, customers, nil
+}
+
+func normalizedVerticesToBoundingBox(vertices []*visionpb.NormalizedVertex) boundingBox {
+	if len(vertices) == 0 {
+		return boundingBox{}
+	}
+	minX, minY := vertices[0].X, vertices[0].Y
+	maxX, maxY := vertices[0].X, vertices[0].Y
+	for _, v := range vertices[1:] {
+		if v.X < minX {
+			minX = v.X
+		}
+		if v.X > maxX {
+			maxX = v.X
+		}
+		if v.Y < minY {
+			minY = v.Y
+		}
+		if v.Y > maxY {
+			maxY = v.Y
+		}
+	}
+	return boundingBox{
+		Left:   float64(minX),
+		Top:    float64(minY),
+		Width:  float64(maxX - minX),
+		Height: float64(maxY - minY),
+	}
+}
@@ -0,0 +1,88 @@
+package functions
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const localDetectorAddrEnvKey = "LOCAL_DETECTOR_ADDR"
+
+// localDetector detects people and faces by calling out to an external
+// inference server (e.g. an ONNX model served over gRPC), so the pipeline
+// can run without depending on any cloud vision API.
+type localDetector struct {
+	client inferenceServiceClient
+}
+
+var (
+	localDetectorConnOnce sync.Once
+	localDetectorConn     *grpc.ClientConn
+	localDetectorConnErr  error
+)
+
+// sharedLocalDetectorConn lazily dials a single connection to
+// LOCAL_DETECTOR_ADDR shared by every localDetector, since grpc.Dial was
+// previously (and wastefully) called once per image, including inside
+// AnalyzeLineImageBatch's concurrent worker pool. grpc.ClientConn is itself
+// already safe for concurrent use.
+func sharedLocalDetectorConn() (*grpc.ClientConn, error) {
+	localDetectorConnOnce.Do(func() {
+		addr := mustEnv(localDetectorAddrEnvKey)
+		localDetectorConn, localDetectorConnErr = grpc.Dial(addr, grpc.WithInsecure())
+		if localDetectorConnErr != nil {
+			localDetectorConnErr = errors.Wrapf(localDetectorConnErr, "dial local detector at %s", addr)
+		}
+	})
+	return localDetectorConn, localDetectorConnErr
+}
+
+func newLocalDetector() (*localDetector, error) {
+	conn, err := sharedLocalDetectorConn()
+	if err != nil {
+		return nil, err
+	}
+	return &localDetector{client: newInferenceServiceClient(conn)}, nil
+}
+
+func (d *localDetector) DetectPeople(ctx context.Context, imgReader io.Reader) ([]*personDetection, []*waitingCustomerMeta, error) {
+	bytes, err := ioutil.ReadAll(imgReader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read img bytes from reader")
+	}
+
+	resp, err := d.client.Detect(ctx, &detectRequest{ImageBytes: bytes})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "call local detector")
+	}
+
+	var people []*personDetection
+	for _, p := range resp.People {
+		people = append(people, &personDetection{
+			Label:      p.Label,
+			Confidence: p.Confidence,
+			BoundingBox: boundingBox{
+				Left:   p.Left,
+				Top:    p.Top,
+				Width:  p.Width,
+				Height: p.Height,
+			},
+		})
+	}
+
+	var customers []*waitingCustomerMeta
+	for _, f := range resp.Faces {
+		customers = append(customers, &waitingCustomerMeta{
+			Gender:           f.Gender,
+			GenderConfidence: f.GenderConfidence,
+			LowestAge:        f.LowestAge,
+			HighestAge:       f.HighestAge,
+			Confidence:       f.Confidence,
+		})
+	}
+	return people, customers, nil
+}
@@ -0,0 +1,91 @@
+package functions
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rekognition"
+	"github.com/pkg/errors"
+)
+
+// rekognitionDetector detects people and faces using AWS Rekognition.
+type rekognitionDetector struct {
+	svc *rekognition.Rekognition
+}
+
+var (
+	awsSessionOnce sync.Once
+	awsSession     *session.Session
+	awsSessionErr  error
+)
+
+// sharedAWSSession lazily creates a single AWS session shared by every
+// rekognitionDetector, since session.NewSession() was previously (and
+// wastefully) called once per image.
+func sharedAWSSession() (*session.Session, error) {
+	awsSessionOnce.Do(func() {
+		awsSession, awsSessionErr = session.NewSession()
+	})
+	return awsSession, awsSessionErr
+}
+
+func newRekognitionDetector() (*rekognitionDetector, error) {
+	sess, err := sharedAWSSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "new aws session")
+	}
+	return &rekognitionDetector{
+		svc: rekognition.New(sess, aws.NewConfig().WithRegion("ap-northeast-1")),
+	}, nil
+}
+
+func (d *rekognitionDetector) DetectPeople(ctx context.Context, imgReader io.Reader) ([]*personDetection, []*waitingCustomerMeta, error) {
+	bytes, err := ioutil.ReadAll(imgReader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read img bytes from reader")
+	}
+
+	detectLabelOutput, err := d.svc.DetectLabelsWithContext(ctx, &rekognition.DetectLabelsInput{Image: &rekognition.Image{Bytes: bytes}})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "detect labels")
+	}
+	var people []*personDetection
+	for _, label := range detectLabelOutput.Labels {
+		for _, instance := range label.Instances {
+			people = append(people, &personDetection{
+				Label:      *label.Name,
+				Confidence: *instance.Confidence,
+				BoundingBox: boundingBox{
+					Left:   *instance.BoundingBox.Left,
+					Top:    *instance.BoundingBox.Top,
+					Width:  *instance.BoundingBox.Width,
+					Height: *instance.BoundingBox.Height,
+				},
+			})
+		}
+	}
+
+	detectFacesOutput, err := d.svc.DetectFacesWithContext(ctx, &rekognition.DetectFacesInput{
+		Attributes: []*string{aws.String("ALL")},
+		Image:      &rekognition.Image{Bytes: bytes},
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "detect faces")
+	}
+
+	var customers []*waitingCustomerMeta
+	for _, faceDetail := range detectFacesOutput.FaceDetails {
+		customers = append(customers, &waitingCustomerMeta{
+			Gender:           *faceDetail.Gender.Value,
+			GenderConfidence: *faceDetail.Gender.Confidence,
+			LowestAge:        *faceDetail.AgeRange.Low,
+			HighestAge:       *faceDetail.AgeRange.High,
+			Confidence:       *faceDetail.Confidence,
+		})
+	}
+	return people, customers, nil
+}
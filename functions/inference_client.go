@@ -0,0 +1,58 @@
+package functions
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// detectRequest/detectResponse and inferenceServiceClient mirror the
+// request/response shapes generated from the local-detector's
+// inference.proto. They're hand-rolled here rather than checked in as
+// generated code since this repo has no protoc toolchain wired up yet.
+
+type detectRequest struct {
+	ImageBytes []byte
+}
+
+type detectedPerson struct {
+	Label      string
+	Confidence float64
+	Left       float64
+	Top        float64
+	Width      float64
+	Height     float64
+}
+
+type detectedFace struct {
+	Gender           string
+	GenderConfidence float64
+	LowestAge        int64
+	HighestAge       int64
+	Confidence       float64
+}
+
+type detectResponse struct {
+	People []*detectedPerson
+	Faces  []*detectedFace
+}
+
+type inferenceServiceClient interface {
+	Detect(ctx context.Context, req *detectRequest) (*detectResponse, error)
+}
+
+type inferenceServiceClientImpl struct {
+	conn *grpc.ClientConn
+}
+
+func newInferenceServiceClient(conn *grpc.ClientConn) inferenceServiceClient {
+	return &inferenceServiceClientImpl{conn: conn}
+}
+
+func (c *inferenceServiceClientImpl) Detect(ctx context.Context, req *detectRequest) (*detectResponse, error) {
+	resp := new(detectResponse)
+	if err := c.conn.Invoke(ctx, "/inference.InferenceService/Detect", req, resp, grpc.CallContentSubtype(jsonCodecName)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,81 @@
+package functions
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeInferenceServer implements the Detect RPC by returning a canned
+// response, so inferenceServiceClientImpl can be tested without an actual
+// ONNX/local detector process.
+type fakeInferenceServer struct {
+	resp *detectResponse
+}
+
+func (s *fakeInferenceServer) Detect(_ context.Context, _ *detectRequest) (*detectResponse, error) {
+	return s.resp, nil
+}
+
+// fakeInferenceServiceDesc hand-rolls the same ServiceDesc protoc would
+// generate from inference.proto, so grpc.Server can dispatch
+// "/inference.InferenceService/Detect" to fakeInferenceServer.
+var fakeInferenceServiceDesc = grpc.ServiceDesc{
+	ServiceName: "inference.InferenceService",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Detect",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(detectRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return srv.(*fakeInferenceServer).Detect(ctx, req)
+			},
+		},
+	},
+}
+
+func TestInferenceServiceClientImpl_Detect(t *testing.T) {
+	want := &detectResponse{
+		People: []*detectedPerson{
+			{Label: "Person", Confidence: 0.91, Left: 0.1, Top: 0.2, Width: 0.3, Height: 0.4},
+		},
+		Faces: []*detectedFace{
+			{Gender: "Male", GenderConfidence: 0.8, LowestAge: 20, HighestAge: 30, Confidence: 0.75},
+		},
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	srv.RegisterService(&fakeInferenceServiceDesc, &fakeInferenceServer{resp: want})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.Dial() }),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	client := newInferenceServiceClient(conn)
+	got, err := client.Detect(context.Background(), &detectRequest{ImageBytes: []byte("image")})
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+
+	if len(got.People) != 1 || *got.People[0] != *want.People[0] {
+		t.Errorf("People = %+v, want %+v", got.People, want.People)
+	}
+	if len(got.Faces) != 1 || *got.Faces[0] != *want.Faces[0] {
+		t.Errorf("Faces = %+v, want %+v", got.Faces, want.Faces)
+	}
+}
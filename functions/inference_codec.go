@@ -0,0 +1,33 @@
+package functions
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// jsonCodecName is registered as a gRPC codec and used as the call's content
+// subtype. detectRequest/detectResponse are plain structs, not
+// proto.Message, so the default "proto" codec can't marshal them (it type
+// asserts to proto.Message with no fallback and panics). Registering a
+// codec keyed by content-subtype lets both this client and the inference
+// server negotiate JSON instead, without needing a protoc toolchain.
+const jsonCodecName = "json"
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return jsonCodecName
+}
@@ -0,0 +1,112 @@
+package functions
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// pipelinesConfigPathEnvKey points at the pipelines.yaml gallery loaded once
+// at cold start. When unset, every shop falls back to defaultPipelineConfig.
+const pipelinesConfigPathEnvKey = "PIPELINES_CONFIG_PATH"
+
+// postProcessConfig tunes the filters applied to raw Detector output before
+// it's counted towards WaitingPeopleNum.
+type postProcessConfig struct {
+	// MinBoxSize drops detections whose bounding box area (as a fraction of
+	// the whole image) is below this, to filter out people in the far
+	// background.
+	MinBoxSize float64 `yaml:"min_box_size"`
+	// DedupIoUThreshold suppresses detections that overlap an
+	// already-kept, higher-confidence detection by at least this IoU.
+	DedupIoUThreshold float64 `yaml:"dedup_iou_threshold"`
+}
+
+// pipelineConfig is a shop's entry in the pipelines.yaml gallery.
+type pipelineConfig struct {
+	DetectorBackend     string            `yaml:"detector_backend"`
+	ConfidenceThreshold float64           `yaml:"confidence_threshold"`
+	CountLabel          string            `yaml:"count_label"`
+	ROI                 []roiPoint        `yaml:"roi"`
+	PostProcess         postProcessConfig `yaml:"post_process"`
+	// CustomerConfidenceThreshold drops face detections below this
+	// confidence before they're written as waitingCustomerMeta rows. It's
+	// separate from ConfidenceThreshold since that one only gates which
+	// detections count towards WaitingPeopleNum.
+	CustomerConfidenceThreshold float64 `yaml:"customer_confidence_threshold"`
+}
+
+// roiPoint is a vertex of a shop's queue-area polygon, normalized to [0,1]
+// fractions of the image width/height.
+type roiPoint struct {
+	X float64 `yaml:"x"`
+	Y float64 `yaml:"y"`
+}
+
+// defaultPipelineConfig reproduces today's hardcoded behavior for shops with
+// no entry in the gallery: no ROI restriction, no dedup, 0.5 confidence,
+// counting the "Person" label.
+var defaultPipelineConfig = &pipelineConfig{
+	ConfidenceThreshold:         0.5,
+	CountLabel:                  "Person",
+	CustomerConfidenceThreshold: 0.5,
+}
+
+var (
+	pipelineGalleryOnce sync.Once
+	pipelineGallery     map[string]*pipelineConfig
+	pipelineGalleryErr  error
+)
+
+// resolvePipeline returns the pipeline config for shopID, loading the
+// pipelines.yaml gallery on first use, or defaultPipelineConfig if the shop
+// has no entry (or no gallery is configured at all).
+func resolvePipeline(shopID string) (*pipelineConfig, error) {
+	pipelineGalleryOnce.Do(func() {
+		pipelineGallery, pipelineGalleryErr = loadPipelineGallery()
+	})
+	if pipelineGalleryErr != nil {
+		return nil, pipelineGalleryErr
+	}
+	if cfg, ok := pipelineGallery[shopID]; ok {
+		return mergeWithDefaultPipelineConfig(cfg), nil
+	}
+	return defaultPipelineConfig, nil
+}
+
+// mergeWithDefaultPipelineConfig fills in any field cfg leaves at its zero
+// value with defaultPipelineConfig's value, so a gallery entry that only
+// sets e.g. roi doesn't also silently zero out the confidence threshold and
+// count label.
+func mergeWithDefaultPipelineConfig(cfg *pipelineConfig) *pipelineConfig {
+	merged := *cfg
+	if merged.ConfidenceThreshold == 0 {
+		merged.ConfidenceThreshold = defaultPipelineConfig.ConfidenceThreshold
+	}
+	if merged.CountLabel == "" {
+		merged.CountLabel = defaultPipelineConfig.CountLabel
+	}
+	if merged.CustomerConfidenceThreshold == 0 {
+		merged.CustomerConfidenceThreshold = defaultPipelineConfig.CustomerConfidenceThreshold
+	}
+	return &merged
+}
+
+func loadPipelineGallery() (map[string]*pipelineConfig, error) {
+	path := os.Getenv(pipelinesConfigPathEnvKey)
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read pipelines gallery at %s", path)
+	}
+	gallery := map[string]*pipelineConfig{}
+	if err := yaml.Unmarshal(raw, &gallery); err != nil {
+		return nil, errors.Wrapf(err, "parse pipelines gallery at %s", path)
+	}
+	return gallery, nil
+}
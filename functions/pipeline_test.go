@@ -0,0 +1,44 @@
+package functions
+
+import "testing"
+
+func TestMergeWithDefaultPipelineConfig(t *testing.T) {
+	cfg := &pipelineConfig{
+		ROI: []roiPoint{{X: 0, Y: 0}, {X: 1, Y: 1}},
+	}
+
+	merged := mergeWithDefaultPipelineConfig(cfg)
+
+	if merged.ConfidenceThreshold != defaultPipelineConfig.ConfidenceThreshold {
+		t.Errorf("ConfidenceThreshold = %v, want default %v", merged.ConfidenceThreshold, defaultPipelineConfig.ConfidenceThreshold)
+	}
+	if merged.CountLabel != defaultPipelineConfig.CountLabel {
+		t.Errorf("CountLabel = %q, want default %q", merged.CountLabel, defaultPipelineConfig.CountLabel)
+	}
+	if merged.CustomerConfidenceThreshold != defaultPipelineConfig.CustomerConfidenceThreshold {
+		t.Errorf("CustomerConfidenceThreshold = %v, want default %v", merged.CustomerConfidenceThreshold, defaultPipelineConfig.CustomerConfidenceThreshold)
+	}
+	if len(merged.ROI) != 2 {
+		t.Errorf("ROI = %+v, want the gallery entry's own ROI to be preserved", merged.ROI)
+	}
+}
+
+func TestMergeWithDefaultPipelineConfig_KeepsExplicitValues(t *testing.T) {
+	cfg := &pipelineConfig{
+		ConfidenceThreshold:         0.7,
+		CountLabel:                  "Face",
+		CustomerConfidenceThreshold: 0.6,
+	}
+
+	merged := mergeWithDefaultPipelineConfig(cfg)
+
+	if merged.ConfidenceThreshold != 0.7 {
+		t.Errorf("ConfidenceThreshold = %v, want the gallery entry's explicit 0.7", merged.ConfidenceThreshold)
+	}
+	if merged.CountLabel != "Face" {
+		t.Errorf("CountLabel = %q, want the gallery entry's explicit %q", merged.CountLabel, "Face")
+	}
+	if merged.CustomerConfidenceThreshold != 0.6 {
+		t.Errorf("CustomerConfidenceThreshold = %v, want the gallery entry's explicit 0.6", merged.CustomerConfidenceThreshold)
+	}
+}
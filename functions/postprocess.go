@@ -0,0 +1,99 @@
+package functions
+
+import (
+	"sort"
+
+	"github.com/k-yomo/line_analyzer/internal/geometry"
+)
+
+// applyPipeline filters raw person detections down to the ones that should
+// count towards a shop's WaitingPeopleNum: matching the configured label,
+// above the confidence threshold, inside the queue ROI (if any), large
+// enough, and deduped against overlapping detections.
+func applyPipeline(people []*personDetection, cfg *pipelineConfig) []*personDetection {
+	filtered := make([]*personDetection, 0, len(people))
+	for _, p := range people {
+		if cfg.CountLabel != "" && p.Label != cfg.CountLabel {
+			continue
+		}
+		if p.Confidence < cfg.ConfidenceThreshold {
+			continue
+		}
+		if cfg.PostProcess.MinBoxSize > 0 && boxArea(p.BoundingBox) < cfg.PostProcess.MinBoxSize {
+			continue
+		}
+		if len(cfg.ROI) > 0 && !pointInPolygon(boxCenter(p.BoundingBox), cfg.ROI) {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+	if cfg.PostProcess.DedupIoUThreshold > 0 {
+		filtered = dedupByIoU(filtered, cfg.PostProcess.DedupIoUThreshold)
+	}
+	return filtered
+}
+
+// filterCustomersByConfidence drops waitingCustomerMeta rows whose face
+// detection confidence is below threshold, so a low threshold in the
+// gallery (or the default) doesn't write a row for every stray face a
+// detector picks up in the background.
+func filterCustomersByConfidence(customers []*waitingCustomerMeta, threshold float64) []*waitingCustomerMeta {
+	filtered := make([]*waitingCustomerMeta, 0, len(customers))
+	for _, cus := range customers {
+		if cus.Confidence < threshold {
+			continue
+		}
+		filtered = append(filtered, cus)
+	}
+	return filtered
+}
+
+func boxArea(b boundingBox) float64 {
+	return b.Width * b.Height
+}
+
+func boxCenter(b boundingBox) roiPoint {
+	return roiPoint{X: b.Left + b.Width/2, Y: b.Top + b.Height/2}
+}
+
+// pointInPolygon reports whether p lies inside polygon using the standard
+// ray-casting algorithm.
+func pointInPolygon(p roiPoint, polygon []roiPoint) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		vi, vj := polygon[i], polygon[j]
+		if (vi.Y > p.Y) != (vj.Y > p.Y) &&
+			p.X < (vj.X-vi.X)*(p.Y-vi.Y)/(vj.Y-vi.Y)+vi.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// dedupByIoU greedily keeps the highest-confidence detection in each
+// overlapping cluster, suppressing any detection whose IoU with an
+// already-kept detection meets or exceeds threshold.
+func dedupByIoU(detections []*personDetection, threshold float64) []*personDetection {
+	sorted := make([]*personDetection, len(detections))
+	copy(sorted, detections)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Confidence > sorted[j].Confidence })
+
+	var kept []*personDetection
+	for _, d := range sorted {
+		overlaps := false
+		for _, k := range kept {
+			if iou(d.BoundingBox, k.BoundingBox) >= threshold {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+func iou(a, b boundingBox) float64 {
+	return geometry.IoU(geometry.Box(a), geometry.Box(b))
+}
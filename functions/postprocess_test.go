@@ -0,0 +1,84 @@
+package functions
+
+import "testing"
+
+func TestPointInPolygon(t *testing.T) {
+	square := []roiPoint{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}}
+
+	tests := []struct {
+		name string
+		p    roiPoint
+		want bool
+	}{
+		{"center", roiPoint{X: 0.5, Y: 0.5}, true},
+		{"outside right", roiPoint{X: 1.5, Y: 0.5}, false},
+		{"outside above", roiPoint{X: 0.5, Y: -0.5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pointInPolygon(tt.p, square); got != tt.want {
+				t.Errorf("pointInPolygon(%+v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupByIoU(t *testing.T) {
+	a := &personDetection{Confidence: 0.9, BoundingBox: boundingBox{Left: 0, Top: 0, Width: 0.2, Height: 0.2}}
+	b := &personDetection{Confidence: 0.6, BoundingBox: boundingBox{Left: 0.01, Top: 0.01, Width: 0.2, Height: 0.2}} // overlaps a
+	c := &personDetection{Confidence: 0.8, BoundingBox: boundingBox{Left: 0.8, Top: 0.8, Width: 0.2, Height: 0.2}}  // distinct
+
+	kept := dedupByIoU([]*personDetection{b, a, c}, 0.5)
+
+	if len(kept) != 2 {
+		t.Fatalf("dedupByIoU kept %d detections, want 2", len(kept))
+	}
+	if kept[0] != a {
+		t.Errorf("kept[0] = %+v, want the higher-confidence overlapping detection %+v", kept[0], a)
+	}
+	if kept[1] != c {
+		t.Errorf("kept[1] = %+v, want the non-overlapping detection %+v", kept[1], c)
+	}
+}
+
+func TestApplyPipeline(t *testing.T) {
+	cfg := &pipelineConfig{
+		ConfidenceThreshold: 0.5,
+		CountLabel:          "Person",
+		ROI:                 []roiPoint{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}},
+		PostProcess: postProcessConfig{
+			MinBoxSize:        0.01,
+			DedupIoUThreshold: 0.5,
+		},
+	}
+	people := []*personDetection{
+		{Label: "Person", Confidence: 0.9, BoundingBox: boundingBox{Left: 0.1, Top: 0.1, Width: 0.2, Height: 0.2}},
+		{Label: "Face", Confidence: 0.9, BoundingBox: boundingBox{Left: 0.1, Top: 0.1, Width: 0.2, Height: 0.2}},       // wrong label
+		{Label: "Person", Confidence: 0.2, BoundingBox: boundingBox{Left: 0.4, Top: 0.4, Width: 0.2, Height: 0.2}},     // below confidence
+		{Label: "Person", Confidence: 0.9, BoundingBox: boundingBox{Left: 1.5, Top: 1.5, Width: 0.2, Height: 0.2}},     // outside ROI
+		{Label: "Person", Confidence: 0.9, BoundingBox: boundingBox{Left: 0.7, Top: 0.7, Width: 0.01, Height: 0.01}},   // too small
+		{Label: "Person", Confidence: 0.95, BoundingBox: boundingBox{Left: 0.11, Top: 0.11, Width: 0.2, Height: 0.2}}, // dupes the first
+	}
+
+	filtered := applyPipeline(people, cfg)
+
+	if len(filtered) != 1 {
+		t.Fatalf("applyPipeline = %d detections, want 1; got %+v", len(filtered), filtered)
+	}
+	if filtered[0].Confidence != 0.95 {
+		t.Errorf("applyPipeline kept confidence %v, want the higher-confidence duplicate (0.95)", filtered[0].Confidence)
+	}
+}
+
+func TestFilterCustomersByConfidence(t *testing.T) {
+	customers := []*waitingCustomerMeta{
+		{Gender: "Male", Confidence: 0.9},
+		{Gender: "Female", Confidence: 0.4},
+	}
+
+	filtered := filterCustomersByConfidence(customers, 0.5)
+
+	if len(filtered) != 1 || filtered[0].Gender != "Male" {
+		t.Errorf("filterCustomersByConfidence = %+v, want only the Male entry", filtered)
+	}
+}
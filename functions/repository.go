@@ -0,0 +1,69 @@
+package functions
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// observationRepositoryBackendEnvKey selects which ObservationRepository
+// implementation AnalyzeLineImage writes through. Valid values are
+// "bigquery" (default), "firestore" and "postgres".
+const observationRepositoryBackendEnvKey = "OBSERVATION_REPOSITORY_BACKEND"
+
+const (
+	observationRepositoryBackendBigQuery  = "bigquery"
+	observationRepositoryBackendFirestore = "firestore"
+	observationRepositoryBackendPostgres  = "postgres"
+)
+
+// imageStoreBackendEnvKey selects which ImageStore implementation
+// AnalyzeLineImage reads the source image from. Valid values are "gcs"
+// (default) and "s3".
+const imageStoreBackendEnvKey = "IMAGE_STORE_BACKEND"
+
+const (
+	imageStoreBackendGCS = "gcs"
+	imageStoreBackendS3  = "s3"
+)
+
+// ObservationRepository persists the results of analyzing a lineObservation.
+// It's the sink side of the pipeline, kept separate from Detector so sinks
+// can be swapped (e.g. to a Postgres table for Grafana) without touching
+// detection.
+type ObservationRepository interface {
+	PutLineObservations(ctx context.Context, observations []*lineObservation) error
+	PutWaitingCustomerMetas(ctx context.Context, customers []*waitingCustomerMeta) error
+	PutWaitingCustomerTracks(ctx context.Context, tracks []*waitingCustomerTrack) error
+}
+
+func newObservationRepository(ctx context.Context) (ObservationRepository, error) {
+	switch backend := os.Getenv(observationRepositoryBackendEnvKey); backend {
+	case "", observationRepositoryBackendBigQuery:
+		return newBigQueryObservationRepository(ctx)
+	case observationRepositoryBackendFirestore:
+		return newFirestoreObservationRepository(ctx)
+	case observationRepositoryBackendPostgres:
+		return newPostgresObservationRepository(ctx)
+	default:
+		return nil, errors.Errorf("unknown %s=%s", observationRepositoryBackendEnvKey, backend)
+	}
+}
+
+// ImageStore reads the source image of a lineObservation.
+type ImageStore interface {
+	NewReader(ctx context.Context, objectName string) (io.ReadCloser, error)
+}
+
+func newImageStore(ctx context.Context, bucket string) (ImageStore, error) {
+	switch backend := os.Getenv(imageStoreBackendEnvKey); backend {
+	case "", imageStoreBackendGCS:
+		return newGCSImageStore(ctx, bucket)
+	case imageStoreBackendS3:
+		return newS3ImageStore(bucket)
+	default:
+		return nil, errors.Errorf("unknown %s=%s", imageStoreBackendEnvKey, backend)
+	}
+}
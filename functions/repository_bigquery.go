@@ -0,0 +1,49 @@
+package functions
+
+import (
+	"context"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+)
+
+// bigQueryObservationRepository persists lineObservations and
+// waitingCustomerMetas to BigQuery, today's default sink.
+type bigQueryObservationRepository struct {
+	dataset *bigquery.Dataset
+}
+
+func newBigQueryObservationRepository(ctx context.Context) (*bigQueryObservationRepository, error) {
+	bqClient, err := bigquery.NewClient(ctx, mustEnv("GCP_PROJECT_ID"))
+	if err != nil {
+		return nil, errors.Wrap(err, "init bigquery client failed")
+	}
+	return &bigQueryObservationRepository{dataset: bqClient.Dataset(fastLaneDataset)}, nil
+}
+
+func (r *bigQueryObservationRepository) PutLineObservations(ctx context.Context, observations []*lineObservation) error {
+	if len(observations) == 0 {
+		return nil
+	}
+	if err := r.dataset.Table(lineObservationTable).Inserter().Put(ctx, observations); err != nil {
+		return errors.Wrap(err, "put analyzed lineObservation data")
+	}
+	return nil
+}
+
+func (r *bigQueryObservationRepository) PutWaitingCustomerMetas(ctx context.Context, customers []*waitingCustomerMeta) error {
+	if err := r.dataset.Table(waitingCustomerMetaTable).Inserter().Put(ctx, customers); err != nil {
+		return errors.Wrap(err, "load analyzed lineObservation data to bq faile")
+	}
+	return nil
+}
+
+func (r *bigQueryObservationRepository) PutWaitingCustomerTracks(ctx context.Context, tracks []*waitingCustomerTrack) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+	if err := r.dataset.Table(waitingCustomerTrackTable).Inserter().Put(ctx, tracks); err != nil {
+		return errors.Wrap(err, "put waitingCustomerTrack data")
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package functions
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/pkg/errors"
+)
+
+const (
+	firestoreLineObservationCollection      = "line_observations"
+	firestoreWaitingCustomerMetaCollection  = "waiting_customer_metas"
+	firestoreWaitingCustomerTrackCollection = "waiting_customer_tracks"
+)
+
+// firestoreObservationRepository persists lineObservations and
+// waitingCustomerMetas to Firestore, for deployments that want a
+// lower-latency sink than BigQuery for serving reads back to a dashboard.
+type firestoreObservationRepository struct {
+	client *firestore.Client
+}
+
+func newFirestoreObservationRepository(ctx context.Context) (*firestoreObservationRepository, error) {
+	client, err := firestore.NewClient(ctx, mustEnv("GCP_PROJECT_ID"))
+	if err != nil {
+		return nil, errors.Wrap(err, "init firestore client failed")
+	}
+	return &firestoreObservationRepository{client: client}, nil
+}
+
+func (r *firestoreObservationRepository) PutLineObservations(ctx context.Context, observations []*lineObservation) error {
+	coll := r.client.Collection(firestoreLineObservationCollection)
+	for _, lo := range observations {
+		if _, err := coll.Doc(lo.ID).Set(ctx, lo); err != nil {
+			return errors.Wrap(err, "put analyzed lineObservation data")
+		}
+	}
+	return nil
+}
+
+func (r *firestoreObservationRepository) PutWaitingCustomerMetas(ctx context.Context, customers []*waitingCustomerMeta) error {
+	coll := r.client.Collection(firestoreWaitingCustomerMetaCollection)
+	for _, cus := range customers {
+		if _, _, err := coll.Add(ctx, cus); err != nil {
+			return errors.Wrap(err, "load analyzed lineObservation data to firestore failed")
+		}
+	}
+	return nil
+}
+
+func (r *firestoreObservationRepository) PutWaitingCustomerTracks(ctx context.Context, tracks []*waitingCustomerTrack) error {
+	coll := r.client.Collection(firestoreWaitingCustomerTrackCollection)
+	for _, trk := range tracks {
+		if _, err := coll.Doc(trk.TrackID).Set(ctx, trk); err != nil {
+			return errors.Wrap(err, "put waitingCustomerTrack data")
+		}
+	}
+	return nil
+}
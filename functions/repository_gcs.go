@@ -0,0 +1,30 @@
+package functions
+
+import (
+	"context"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+)
+
+// gcsImageStore reads source images from Cloud Storage, today's default.
+type gcsImageStore struct {
+	bucket *storage.BucketHandle
+}
+
+func newGCSImageStore(ctx context.Context, bucket string) (*gcsImageStore, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "init gcs client")
+	}
+	return &gcsImageStore{bucket: client.Bucket(bucket)}, nil
+}
+
+func (s *gcsImageStore) NewReader(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	reader, err := s.bucket.Object(objectName).NewReader(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "new %s reader failed", objectName)
+	}
+	return reader, nil
+}
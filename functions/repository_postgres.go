@@ -0,0 +1,65 @@
+package functions
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+)
+
+// postgresObservationRepository persists lineObservations and
+// waitingCustomerMetas to a Postgres time-series table, for deployments
+// that feed a Grafana dashboard directly rather than going through BigQuery.
+type postgresObservationRepository struct {
+	pool *pgxpool.Pool
+}
+
+func newPostgresObservationRepository(ctx context.Context) (*postgresObservationRepository, error) {
+	pool, err := pgxpool.Connect(ctx, mustEnv("POSTGRES_DSN"))
+	if err != nil {
+		return nil, errors.Wrap(err, "init postgres pool failed")
+	}
+	return &postgresObservationRepository{pool: pool}, nil
+}
+
+func (r *postgresObservationRepository) PutLineObservations(ctx context.Context, observations []*lineObservation) error {
+	for _, lo := range observations {
+		_, err := r.pool.Exec(ctx,
+			`INSERT INTO line_observation (id, shop_id, waiting_people_num, observed_at, created_at)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			lo.ID, lo.ShopID, lo.WaitingPeopleNum, lo.ObservedAt, lo.CreatedAt,
+		)
+		if err != nil {
+			return errors.Wrap(err, "put analyzed lineObservation data")
+		}
+	}
+	return nil
+}
+
+func (r *postgresObservationRepository) PutWaitingCustomerMetas(ctx context.Context, customers []*waitingCustomerMeta) error {
+	for _, cus := range customers {
+		_, err := r.pool.Exec(ctx,
+			`INSERT INTO waiting_customer_meta (line_observation_id, gender, gender_confidence, lowest_age, highest_age, confidence)
+			 VALUES ($1, $2, $3, $4, $5, $6)`,
+			cus.LineObservationID, cus.Gender, cus.GenderConfidence, cus.LowestAge, cus.HighestAge, cus.Confidence,
+		)
+		if err != nil {
+			return errors.Wrap(err, "load analyzed lineObservation data to postgres failed")
+		}
+	}
+	return nil
+}
+
+func (r *postgresObservationRepository) PutWaitingCustomerTracks(ctx context.Context, tracks []*waitingCustomerTrack) error {
+	for _, trk := range tracks {
+		_, err := r.pool.Exec(ctx,
+			`INSERT INTO waiting_customer_track (track_id, shop_id, first_seen_at, last_seen_at, dwell_seconds)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			trk.TrackID, trk.ShopID, trk.FirstSeenAt, trk.LastSeenAt, trk.DwellSeconds,
+		)
+		if err != nil {
+			return errors.Wrap(err, "put waitingCustomerTrack data")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package functions
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+)
+
+// s3ImageStore reads source images from S3, for deployments that don't
+// otherwise depend on GCP.
+type s3ImageStore struct {
+	svc    *s3.S3
+	bucket string
+}
+
+func newS3ImageStore(bucket string) (*s3ImageStore, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "new aws session")
+	}
+	return &s3ImageStore{svc: s3.New(sess), bucket: bucket}, nil
+}
+
+func (s *s3ImageStore) NewReader(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	out, err := s.svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &objectName,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "new %s reader failed", objectName)
+	}
+	return out.Body, nil
+}
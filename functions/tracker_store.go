@@ -0,0 +1,136 @@
+package functions
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/k-yomo/line_analyzer/tracker"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const waitingCustomerTrackTable = "waiting_customer_track"
+
+// trackerStateCollection holds one document per shop, keyed by shopID,
+// storing that shop's currently active (not yet retired) tracks.
+const trackerStateCollection = "tracker_state"
+
+// waitingCustomerTrack is a completed visit: a person tracked across frames
+// from when they joined the line to when they left it.
+type waitingCustomerTrack struct {
+	TrackID      string    `bigquery:"track_id"`
+	ShopID       string    `bigquery:"shop_id"`
+	FirstSeenAt  time.Time `bigquery:"first_seen_at"`
+	LastSeenAt   time.Time `bigquery:"last_seen_at"`
+	DwellSeconds float64   `bigquery:"dwell_seconds"`
+}
+
+// trackerStateDoc is how a shop's active tracks are persisted between
+// invocations. Cloud Functions instances scale horizontally and recycle
+// freely, so a shop's frames can land on any instance; keeping Tracker
+// state only in process memory would mean tracks never mature past
+// whichever instance happened to see the next frame.
+type trackerStateDoc struct {
+	Tracks []tracker.State `firestore:"tracks"`
+}
+
+var (
+	trackerFirestoreClientOnce sync.Once
+	trackerFirestoreClient     *firestore.Client
+	trackerFirestoreClientErr  error
+)
+
+// sharedTrackerFirestoreClient lazily creates a single Firestore client used
+// to persist tracker state, shared across invocations in a warm container.
+// Firestore is used here (rather than the configured ObservationRepository)
+// because tracker state needs point reads/writes keyed by shopID, which
+// BigQuery and the Postgres append-only inserts don't support.
+func sharedTrackerFirestoreClient(ctx context.Context) (*firestore.Client, error) {
+	trackerFirestoreClientOnce.Do(func() {
+		trackerFirestoreClient, trackerFirestoreClientErr = firestore.NewClient(ctx, mustEnv("GCP_PROJECT_ID"))
+	})
+	return trackerFirestoreClient, trackerFirestoreClientErr
+}
+
+// loadTrackerTx restores shopID's Tracker from its last-persisted state
+// within an in-flight transaction, so a frame handled by a different (or
+// freshly cold-started) instance still continues the same tracks instead of
+// starting over.
+func loadTrackerTx(tx *firestore.Transaction, doc *firestore.DocumentRef) (*tracker.Tracker, error) {
+	t := tracker.New()
+	snap, err := tx.Get(doc)
+	if status.Code(err) == codes.NotFound {
+		return t, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "load tracker state for shop %s", doc.ID)
+	}
+	var state trackerStateDoc
+	if err := snap.DataTo(&state); err != nil {
+		return nil, errors.Wrapf(err, "decode tracker state for shop %s", doc.ID)
+	}
+	t.Restore(state.Tracks)
+	return t, nil
+}
+
+// updateTracks feeds this frame's pipeline-filtered person detections into
+// the shop's tracker and persists the tracker's resulting state, and
+// returns a row for every track that just retired, with its final dwell
+// time. The load-update-save sequence runs inside a Firestore transaction so
+// two concurrent invocations for the same shop (plausible since GCS/Pub/Sub
+// triggers for a shop's frames aren't serialized against each other) can't
+// both load the same snapshot and have one clobber the other's update.
+//
+// Callers that process more than one frame for the same shop (e.g.
+// AnalyzeLineImageBatch) must call this once per frame, sequentially, in
+// observedAt order: the tracker's Kalman prediction and greedy IoU matching
+// both assume each call is the next frame chronologically.
+func updateTracks(ctx context.Context, shopID string, observedAt time.Time, people []*personDetection) ([]*waitingCustomerTrack, error) {
+	client, err := sharedTrackerFirestoreClient(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "init tracker state client")
+	}
+
+	detections := make([]tracker.Detection, len(people))
+	for i, p := range people {
+		detections[i] = tracker.Detection{
+			Left:       p.BoundingBox.Left,
+			Top:        p.BoundingBox.Top,
+			Width:      p.BoundingBox.Width,
+			Height:     p.BoundingBox.Height,
+			Confidence: p.Confidence,
+		}
+	}
+
+	doc := client.Collection(trackerStateCollection).Doc(shopID)
+	var retired []*tracker.Track
+	err = client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		t, err := loadTrackerTx(tx, doc)
+		if err != nil {
+			return err
+		}
+		retired = t.Update(observedAt, detections)
+		return tx.Set(doc, trackerStateDoc{Tracks: t.Snapshot()})
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "update tracker state for shop %s", shopID)
+	}
+
+	if len(retired) == 0 {
+		return nil, nil
+	}
+	rows := make([]*waitingCustomerTrack, len(retired))
+	for i, trk := range retired {
+		rows[i] = &waitingCustomerTrack{
+			TrackID:      trk.ID,
+			ShopID:       shopID,
+			FirstSeenAt:  trk.FirstSeenAt,
+			LastSeenAt:   trk.LastSeenAt,
+			DwellSeconds: trk.DwellSeconds(),
+		}
+	}
+	return rows, nil
+}
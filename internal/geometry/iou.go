@@ -0,0 +1,45 @@
+// Package geometry holds small 2D geometry helpers shared by the detection
+// post-processing pipeline (functions) and the cross-frame tracker
+// (tracker), so the two packages don't carry their own copies of the same
+// math.
+package geometry
+
+// Box is an axis-aligned rectangle, normalized to [0,1] fractions of the
+// image width/height.
+type Box struct {
+	Left, Top, Width, Height float64
+}
+
+// IoU returns the intersection-over-union of a and b, or 0 if they don't
+// overlap.
+func IoU(a, b Box) float64 {
+	left := Max(a.Left, b.Left)
+	top := Max(a.Top, b.Top)
+	right := Min(a.Left+a.Width, b.Left+b.Width)
+	bottom := Min(a.Top+a.Height, b.Top+b.Height)
+	if right <= left || bottom <= top {
+		return 0
+	}
+	intersection := (right - left) * (bottom - top)
+	union := a.Width*a.Height + b.Width*b.Height - intersection
+	if union <= 0 {
+		return 0
+	}
+	return intersection / union
+}
+
+// Max returns the larger of a and b. Go 1.13 has no builtin for this.
+func Max(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Min returns the smaller of a and b. Go 1.13 has no builtin for this.
+func Min(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
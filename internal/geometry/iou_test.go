@@ -0,0 +1,43 @@
+package geometry
+
+import "testing"
+
+func TestIoU(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Box
+		want float64
+	}{
+		{
+			name: "identical boxes",
+			a:    Box{Left: 0, Top: 0, Width: 1, Height: 1},
+			b:    Box{Left: 0, Top: 0, Width: 1, Height: 1},
+			want: 1,
+		},
+		{
+			name: "disjoint boxes",
+			a:    Box{Left: 0, Top: 0, Width: 1, Height: 1},
+			b:    Box{Left: 2, Top: 2, Width: 1, Height: 1},
+			want: 0,
+		},
+		{
+			name: "quarter overlap",
+			a:    Box{Left: 0, Top: 0, Width: 1, Height: 1},
+			b:    Box{Left: 0.5, Top: 0.5, Width: 1, Height: 1},
+			want: 0.25 / 1.75,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IoU(tt.a, tt.b); !almostEqual(got, tt.want) {
+				t.Errorf("IoU(%+v, %+v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	const eps = 1e-9
+	diff := a - b
+	return diff > -eps && diff < eps
+}
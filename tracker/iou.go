@@ -0,0 +1,10 @@
+package tracker
+
+import "github.com/k-yomo/line_analyzer/internal/geometry"
+
+func iou(a, b Detection) float64 {
+	box := func(d Detection) geometry.Box {
+		return geometry.Box{Left: d.Left, Top: d.Top, Width: d.Width, Height: d.Height}
+	}
+	return geometry.IoU(box(a), box(b))
+}
@@ -0,0 +1,15 @@
+package tracker
+
+import "testing"
+
+func TestIoU(t *testing.T) {
+	a := Detection{Left: 0, Top: 0, Width: 1, Height: 1}
+	b := Detection{Left: 0.5, Top: 0.5, Width: 1, Height: 1}
+
+	if got, want := iou(a, a), 1.0; got != want {
+		t.Errorf("iou(a, a) = %v, want %v", got, want)
+	}
+	if got := iou(a, b); got <= 0 || got >= 1 {
+		t.Errorf("iou(a, b) = %v, want a partial overlap strictly between 0 and 1", got)
+	}
+}
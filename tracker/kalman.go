@@ -0,0 +1,97 @@
+package tracker
+
+const (
+	processNoise     = 1e-2
+	measurementNoise = 1e-1
+)
+
+// kalmanFilter smooths a track's box centroid (x, y) with a constant
+// velocity model; width/height are carried through from the latest
+// detection unfiltered. Position and velocity are filtered jointly: posVar,
+// velVar and covar are the entries of the 2x2 state covariance matrix for
+// [position, velocity], shared by both axes since x and y use identical
+// process/measurement noise and so evolve identically.
+type kalmanFilter struct {
+	x, y, vx, vy  float64
+	width, height float64
+	posVar        float64
+	velVar        float64
+	covar         float64
+}
+
+func newKalmanFilter(d Detection) *kalmanFilter {
+	return &kalmanFilter{
+		x:      d.Left + d.Width/2,
+		y:      d.Top + d.Height/2,
+		width:  d.Width,
+		height: d.Height,
+		posVar: 1,
+		velVar: 1,
+	}
+}
+
+// predict advances the filter by one frame and returns the predicted
+// bounding box, used as the matching target before the true detection for
+// this frame is known.
+func (k *kalmanFilter) predict() Detection {
+	k.x += k.vx
+	k.y += k.vy
+
+	// Propagate the state covariance through the constant-velocity
+	// transition matrix [[1,1],[0,1]]: P' = F*P*F^T + Q.
+	k.posVar, k.covar, k.velVar = k.posVar+2*k.covar+k.velVar+processNoise, k.covar+k.velVar, k.velVar+processNoise
+
+	return Detection{
+		Left:   k.x - k.width/2,
+		Top:    k.y - k.height/2,
+		Width:  k.width,
+		Height: k.height,
+	}
+}
+
+// update corrects the filter state with an observed detection. Velocity is
+// corrected from the same residual as position, each with its own Kalman
+// gain derived from the shared covariance matrix, rather than being
+// re-derived from consecutive corrected positions: the latter only
+// recovers a fraction of the true velocity each frame since the position
+// correction it's based on is itself damped by the position gain.
+func (k *kalmanFilter) update(d Detection) {
+	measuredX := d.Left + d.Width/2
+	measuredY := d.Top + d.Height/2
+
+	posGain := k.posVar / (k.posVar + measurementNoise)
+	velGain := k.covar / (k.posVar + measurementNoise)
+
+	k.vx += velGain * (measuredX - k.x)
+	k.vy += velGain * (measuredY - k.y)
+	k.x += posGain * (measuredX - k.x)
+	k.y += posGain * (measuredY - k.y)
+
+	k.velVar -= velGain * k.covar
+	k.covar = (1 - posGain) * k.covar
+	k.posVar = (1 - posGain) * k.posVar
+
+	k.width = d.Width
+	k.height = d.Height
+}
+
+// kalmanState is a serializable snapshot of a kalmanFilter's fields.
+type kalmanState struct {
+	X, Y, VX, VY, Width, Height, PosVar, VelVar, Covar float64
+}
+
+func (k *kalmanFilter) state() kalmanState {
+	return kalmanState{
+		X: k.x, Y: k.y, VX: k.vx, VY: k.vy,
+		Width: k.width, Height: k.height,
+		PosVar: k.posVar, VelVar: k.velVar, Covar: k.covar,
+	}
+}
+
+func kalmanFromState(s kalmanState) *kalmanFilter {
+	return &kalmanFilter{
+		x: s.X, y: s.Y, vx: s.VX, vy: s.VY,
+		width: s.Width, height: s.Height,
+		posVar: s.PosVar, velVar: s.VelVar, covar: s.Covar,
+	}
+}
@@ -0,0 +1,33 @@
+package tracker
+
+import "testing"
+
+func TestKalmanFilter_PredictThenUpdateConvergesTowardMeasurement(t *testing.T) {
+	k := newKalmanFilter(Detection{Left: 0, Top: 0, Width: 0.1, Height: 0.1})
+
+	// A centroid that moves by a consistent step every frame should make the
+	// filter's velocity estimate converge towards that step.
+	step := 0.05
+	x := 0.0
+	for i := 0; i < 20; i++ {
+		x += step
+		k.predict()
+		k.update(Detection{Left: x - 0.05, Top: 0, Width: 0.1, Height: 0.1})
+	}
+
+	if diff := k.vx - step; diff > 0.01 || diff < -0.01 {
+		t.Errorf("vx = %v, want close to the true step %v", k.vx, step)
+	}
+}
+
+func TestKalmanFilter_StateRoundTrip(t *testing.T) {
+	k := newKalmanFilter(Detection{Left: 0.1, Top: 0.2, Width: 0.3, Height: 0.4})
+	k.predict()
+	k.update(Detection{Left: 0.12, Top: 0.21, Width: 0.3, Height: 0.4})
+
+	restored := kalmanFromState(k.state())
+
+	if *restored != *k {
+		t.Errorf("kalmanFromState(k.state()) = %+v, want %+v", *restored, *k)
+	}
+}
@@ -0,0 +1,193 @@
+// Package tracker associates per-frame person detections into stable tracks
+// across a sliding window of frames, so a shop's headcount snapshots can be
+// turned into dwell-time analytics.
+package tracker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// Detection is a single bounding box observed in one frame, normalized to
+// [0,1] fractions of the image width/height.
+type Detection struct {
+	Left, Top, Width, Height float64
+	Confidence               float64
+}
+
+// Track is a person followed across frames by IoU matching plus a Kalman
+// filter on its box centroid.
+type Track struct {
+	ID          string
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+	BoundingBox Detection
+
+	kalman       *kalmanFilter
+	missedFrames int
+}
+
+// DwellSeconds is how long the track has been observed for, so far.
+func (t *Track) DwellSeconds() float64 {
+	return t.LastSeenAt.Sub(t.FirstSeenAt).Seconds()
+}
+
+const (
+	defaultIoUThreshold    = 0.3
+	defaultMaxMissedFrames = 3
+)
+
+// Tracker associates detections across frames for a single shop's camera
+// feed, assigning stable track IDs. Update is safe to call concurrently,
+// but callers still need to feed it frames in non-decreasing `now` order:
+// the Kalman prediction and greedy IoU matching both assume each call is
+// the next frame chronologically, not just the next call to arrive.
+type Tracker struct {
+	IoUThreshold    float64
+	MaxMissedFrames int
+
+	mu     sync.Mutex
+	tracks []*Track
+}
+
+// New builds a Tracker with the defaults described in the package backlog:
+// an IoU threshold of 0.3 and retiring tracks once they have missed more
+// than 3 consecutive frames.
+func New() *Tracker {
+	return &Tracker{
+		IoUThreshold:    defaultIoUThreshold,
+		MaxMissedFrames: defaultMaxMissedFrames,
+	}
+}
+
+// Update advances every active track's Kalman prediction, greedily matches
+// them against this frame's detections by IoU (highest IoU first), spawns
+// new tracks for unmatched detections, and retires tracks unseen for more
+// than MaxMissedFrames consecutive frames. It returns the tracks retired by this
+// call, each carrying a final DwellSeconds.
+func (t *Tracker) Update(now time.Time, detections []Detection) (retired []*Track) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	predicted := make([]Detection, len(t.tracks))
+	for i, trk := range t.tracks {
+		predicted[i] = trk.kalman.predict()
+	}
+
+	type candidate struct {
+		trackIdx, detIdx int
+		iou              float64
+	}
+	var candidates []candidate
+	for i := range t.tracks {
+		for j := range detections {
+			if v := iou(predicted[i], detections[j]); v >= t.IoUThreshold {
+				candidates = append(candidates, candidate{i, j, v})
+			}
+		}
+	}
+	sort.Slice(candidates, func(a, b int) bool { return candidates[a].iou > candidates[b].iou })
+
+	matchedTrack := make(map[int]bool, len(t.tracks))
+	matchedDetection := make(map[int]bool, len(detections))
+	for _, c := range candidates {
+		if matchedTrack[c.trackIdx] || matchedDetection[c.detIdx] {
+			continue
+		}
+		matchedTrack[c.trackIdx] = true
+		matchedDetection[c.detIdx] = true
+
+		trk := t.tracks[c.trackIdx]
+		trk.kalman.update(detections[c.detIdx])
+		trk.BoundingBox = detections[c.detIdx]
+		trk.LastSeenAt = now
+		trk.missedFrames = 0
+	}
+
+	var stillActive []*Track
+	for i, trk := range t.tracks {
+		if !matchedTrack[i] {
+			trk.missedFrames++
+		}
+		if trk.missedFrames > t.MaxMissedFrames {
+			retired = append(retired, trk)
+			continue
+		}
+		stillActive = append(stillActive, trk)
+	}
+
+	for j, det := range detections {
+		if matchedDetection[j] {
+			continue
+		}
+		stillActive = append(stillActive, &Track{
+			ID:          xid.New().String(),
+			FirstSeenAt: now,
+			LastSeenAt:  now,
+			BoundingBox: det,
+			kalman:      newKalmanFilter(det),
+		})
+	}
+
+	t.tracks = stillActive
+	return retired
+}
+
+// State is a serializable snapshot of a single active Track, for
+// persisting a Tracker's state between invocations of a horizontally
+// scaled, frequently-recycled process (e.g. a Cloud Function), where a
+// shop's frames can land on a different instance from one call to the
+// next.
+type State struct {
+	ID           string
+	FirstSeenAt  time.Time
+	LastSeenAt   time.Time
+	BoundingBox  Detection
+	MissedFrames int
+	Kalman       kalmanState
+}
+
+// Snapshot returns the state of every currently active track, suitable for
+// persisting and later passing to Restore.
+func (t *Tracker) Snapshot() []State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	states := make([]State, len(t.tracks))
+	for i, trk := range t.tracks {
+		states[i] = State{
+			ID:           trk.ID,
+			FirstSeenAt:  trk.FirstSeenAt,
+			LastSeenAt:   trk.LastSeenAt,
+			BoundingBox:  trk.BoundingBox,
+			MissedFrames: trk.missedFrames,
+			Kalman:       trk.kalman.state(),
+		}
+	}
+	return states
+}
+
+// Restore replaces the Tracker's active tracks with the given states, as
+// previously returned by Snapshot. It's meant to be called once, right
+// after New, before the first Update.
+func (t *Tracker) Restore(states []State) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracks := make([]*Track, len(states))
+	for i, s := range states {
+		tracks[i] = &Track{
+			ID:          s.ID,
+			FirstSeenAt: s.FirstSeenAt,
+			LastSeenAt:  s.LastSeenAt,
+			BoundingBox: s.BoundingBox,
+
+			kalman:       kalmanFromState(s.Kalman),
+			missedFrames: s.MissedFrames,
+		}
+	}
+	t.tracks = tracks
+}
@@ -0,0 +1,106 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+)
+
+func det(left, top float64) Detection {
+	return Detection{Left: left, Top: top, Width: 0.1, Height: 0.1, Confidence: 0.9}
+}
+
+func TestTracker_MatchesOverlappingDetectionToExistingTrack(t *testing.T) {
+	tr := New()
+	base := time.Now()
+
+	tr.Update(base, []Detection{det(0, 0)})
+	if len(tr.tracks) != 1 {
+		t.Fatalf("after first frame, tracks = %d, want 1", len(tr.tracks))
+	}
+	firstID := tr.tracks[0].ID
+
+	// A detection that's barely moved should match the existing track rather
+	// than spawning a new one.
+	tr.Update(base.Add(time.Second), []Detection{det(0.01, 0.01)})
+
+	if len(tr.tracks) != 1 {
+		t.Fatalf("after second frame, tracks = %d, want 1", len(tr.tracks))
+	}
+	if tr.tracks[0].ID != firstID {
+		t.Errorf("track ID = %s, want the same track to persist across frames (%s)", tr.tracks[0].ID, firstID)
+	}
+}
+
+func TestTracker_SpawnsNewTrackForUnmatchedDetection(t *testing.T) {
+	tr := New()
+	base := time.Now()
+
+	tr.Update(base, []Detection{det(0, 0)})
+	tr.Update(base.Add(time.Second), []Detection{det(0, 0), det(0.9, 0.9)})
+
+	if len(tr.tracks) != 2 {
+		t.Fatalf("tracks = %d, want 2 (one continuing, one newly spawned)", len(tr.tracks))
+	}
+}
+
+func TestTracker_RetiresTrackAfterMaxMissedFrames(t *testing.T) {
+	tr := New()
+	base := time.Now()
+
+	tr.Update(base, []Detection{det(0, 0)})
+
+	var retired []*Track
+	for i := 1; i <= tr.MaxMissedFrames+1; i++ {
+		retired = tr.Update(base.Add(time.Duration(i)*time.Second), nil)
+	}
+
+	if len(retired) != 1 {
+		t.Fatalf("retired = %d, want 1", len(retired))
+	}
+	if len(tr.tracks) != 0 {
+		t.Errorf("tracks = %d, want 0 after retirement", len(tr.tracks))
+	}
+	wantDwell := retired[0].LastSeenAt.Sub(retired[0].FirstSeenAt).Seconds()
+	if retired[0].DwellSeconds() != wantDwell {
+		t.Errorf("DwellSeconds = %v, want %v", retired[0].DwellSeconds(), wantDwell)
+	}
+}
+
+func TestTracker_SnapshotRestoreRoundTrip(t *testing.T) {
+	tr := New()
+	base := time.Now()
+	tr.Update(base, []Detection{det(0, 0), det(0.5, 0.5)})
+	tr.Update(base.Add(time.Second), []Detection{det(0.01, 0.01), det(0.51, 0.51)})
+
+	states := tr.Snapshot()
+	if len(states) != 2 {
+		t.Fatalf("Snapshot = %d states, want 2", len(states))
+	}
+
+	restored := New()
+	restored.Restore(states)
+
+	// Feeding the same next frame to both trackers should match the same
+	// tracks by ID, proving Restore recovered both the bounding boxes and
+	// the Kalman velocity state needed for prediction.
+	wantRetired := tr.Update(base.Add(2*time.Second), []Detection{det(0.02, 0.02), det(0.52, 0.52)})
+	gotRetired := restored.Update(base.Add(2*time.Second), []Detection{det(0.02, 0.02), det(0.52, 0.52)})
+
+	if len(wantRetired) != 0 || len(gotRetired) != 0 {
+		t.Fatalf("unexpected retirements: want %d, got %d", len(wantRetired), len(gotRetired))
+	}
+	if len(restored.tracks) != 2 {
+		t.Fatalf("restored tracks = %d, want 2", len(restored.tracks))
+	}
+	for _, s := range states {
+		found := false
+		for _, trk := range restored.tracks {
+			if trk.ID == s.ID {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("restored track %s not found after Restore", s.ID)
+		}
+	}
+}